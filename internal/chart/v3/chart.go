@@ -0,0 +1,82 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+// File represents a file as a name/value pair.
+//
+// By convention, name is a relative path within the scope of a chart's
+// base directory.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Chart is a helm package that contains metadata, a default config, zero or
+// more optionally parameterizable templates, and zero or more charts
+// (dependencies).
+type Chart struct {
+	// Raw contains the raw, unprocessed file contents of this chart, as
+	// loaded.
+	Raw []*File
+	// Metadata is the contents of Chart.yaml.
+	Metadata *Metadata
+	// Lock is the contents of Chart.lock.
+	Lock *Lock
+	// Templates is a list of files that contain template bodies.
+	Templates []*File
+	// Values are default config for this chart, loaded from values.yaml.
+	Values map[string]interface{}
+	// Schema is an optional JSON schema for validating values.yaml.
+	Schema []byte
+	// Files are miscellaneous files in a chart archive, such as README.md.
+	Files []*File
+	// Provenance is the result of verifying this chart's .prov file, set by
+	// loader.VerifyingLoader when that verification succeeds. It is nil for a
+	// chart loaded without verification, and for one loaded under
+	// VerifyIfPresent that has no .prov file.
+	Provenance *Provenance
+
+	parent       *Chart
+	dependencies []*Chart
+}
+
+// Name returns the name of the chart.
+func (ch *Chart) Name() string {
+	if ch.Metadata == nil {
+		return ""
+	}
+	return ch.Metadata.Name
+}
+
+// Dependencies are the charts that this chart depends on.
+func (ch *Chart) Dependencies() []*Chart { return ch.dependencies }
+
+// AddDependency sets dep's parent to ch and appends dep to ch's
+// dependencies.
+func (ch *Chart) AddDependency(dep *Chart) {
+	dep.parent = ch
+	ch.dependencies = append(ch.dependencies, dep)
+}
+
+// Parent returns the chart that depends on ch, or nil if ch is the root
+// chart.
+func (ch *Chart) Parent() *Chart { return ch.parent }
+
+// Validate validates the metadata.
+func (ch *Chart) Validate() error {
+	return ch.Metadata.Validate()
+}
@@ -0,0 +1,449 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/internal/chart/v3"
+)
+
+// Reference identifies the chart a ChartBuilder should resolve. It is
+// implemented by LocalReference and RemoteReference.
+type Reference interface {
+	isReference()
+}
+
+// LocalReference points at a chart that is already available on disk, as
+// either an unpacked directory or a packaged .tgz.
+type LocalReference struct {
+	// Path is the path to the chart directory or archive.
+	Path string
+}
+
+func (LocalReference) isReference() {}
+
+// RemoteReference points at a chart published to an HTTP chart repository
+// or an OCI registry. An OCI chart is denoted by a URL using the oci://
+// scheme.
+type RemoteReference struct {
+	// URL is the chart repository URL (for HTTP repos) or the registry
+	// reference (for OCI, e.g. oci://registry.example.com/charts).
+	URL string
+	// Name is the chart name.
+	Name string
+	// Version is an exact version or a constraint range understood by
+	// github.com/Masterminds/semver/v3 (e.g. "~1.2.0", ">=1.0.0 <2.0.0").
+	// An empty string resolves to the highest available non-prerelease
+	// version. This resolution is done by RemoteBuilder only: OCIBuilder
+	// appends Version to the OCI reference as a tag, as given, and leaves the
+	// tag off when Version is empty.
+	Version string
+}
+
+func (RemoteReference) isReference() {}
+
+// BuildOptions configures a ChartBuilder.Build call.
+type BuildOptions struct {
+	// Reference selects the chart to build.
+	Reference Reference
+	// ValuesFiles are merged over the chart's own values.yaml, in order,
+	// using the same precedence rules as MergeMaps.
+	ValuesFiles []string
+}
+
+// BuildResult is the outcome of a successful ChartBuilder.Build call.
+type BuildResult struct {
+	// Chart is the fully loaded chart.
+	Chart *chart.Chart
+	// Path is where the packaged (or unpacked) chart lives once Build
+	// returns. For LocalBuilder this is the input path and the caller must
+	// not remove it. For RemoteBuilder and OCIBuilder it is a temporary
+	// file created for this Build call; the caller owns it and is
+	// responsible for calling os.Remove(Path) once done with it.
+	Path string
+	// Digest is the SHA-256 digest of the packaged chart, hex encoded. It is
+	// empty when Path is an unpacked directory. OCIBuilder reports the digest
+	// returned by OCIPuller instead, in the form the puller gives it (e.g.
+	// "sha256:<hex>"), whenever that digest is non-empty.
+	Digest string
+	// Version is the resolved chart version.
+	Version string
+	// Values is the result of merging the chart's default values with
+	// BuildOptions.ValuesFiles.
+	Values map[string]interface{}
+}
+
+// ChartBuilder resolves a Reference into a fully loaded chart. Unlike
+// ChartLoader, which only reads bytes that are already available,
+// a ChartBuilder may need to fetch those bytes first.
+type ChartBuilder interface {
+	Build(ctx context.Context, opts BuildOptions) (*BuildResult, error)
+}
+
+// Getter fetches the raw bytes served at url, so that RemoteBuilder can be
+// driven by the same abstraction regardless of transport. The only
+// implementation in this package is FileGetter, for file:// URLs and bare
+// paths; there is no HTTP implementation here. OCI registries are pulled
+// through OCIPuller instead: resolving a manifest and fetching a
+// content-addressed blob is a different shape of operation than fetching
+// arbitrary bytes by URL, so OCIBuilder doesn't use Getter.
+type Getter interface {
+	Get(ctx context.Context, url string) (io.Reader, error)
+}
+
+// FileGetter implements Getter by reading straight off the local
+// filesystem. It understands both file:// URLs and bare paths, and is the
+// Getter a chart repository mirrored onto disk (or a test) would use.
+type FileGetter struct{}
+
+// Get implements Getter.
+func (FileGetter) Get(_ context.Context, rawURL string) (io.Reader, error) {
+	p := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme == "file" {
+		p = u.Path
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// LocalBuilder builds a chart already present on disk. It is the
+// ChartBuilder counterpart of Load/Loader.
+type LocalBuilder struct{}
+
+// Build implements ChartBuilder.
+func (b LocalBuilder) Build(_ context.Context, opts BuildOptions) (*BuildResult, error) {
+	ref, ok := opts.Reference.(LocalReference)
+	if !ok {
+		return nil, fmt.Errorf("local builder requires a LocalReference, got %T", opts.Reference)
+	}
+
+	c, err := Load(ref.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return finishBuild(c, ref.Path, c.Metadata.Version, opts)
+}
+
+// RemoteBuilder builds a chart published to an HTTP chart repository,
+// resolving the version against the repository index and fetching the
+// matching archive through Getter.
+type RemoteBuilder struct {
+	// Getter retrieves the repository index and the chart archive.
+	Getter Getter
+}
+
+// Build implements ChartBuilder.
+func (b RemoteBuilder) Build(ctx context.Context, opts BuildOptions) (*BuildResult, error) {
+	ref, ok := opts.Reference.(RemoteReference)
+	if !ok {
+		return nil, fmt.Errorf("remote builder requires a RemoteReference, got %T", opts.Reference)
+	}
+	if b.Getter == nil {
+		return nil, fmt.Errorf("remote builder requires a Getter")
+	}
+
+	chartURL, version, err := resolveRepoChartURL(ctx, b.Getter, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := b.Getter.Get(ctx, chartURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", chartURL, err)
+	}
+
+	path, err := writeTempArchive(ref.Name, r)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	result, err := finishBuild(c, path, version, opts)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return result, nil
+}
+
+// repoIndex is the subset of a chart repository's index.yaml this package
+// needs to resolve a chart name/version to an archive URL.
+type repoIndex struct {
+	Entries map[string][]repoIndexEntry `json:"entries"`
+}
+
+// repoIndexEntry is one version of one chart in a repoIndex.
+type repoIndexEntry struct {
+	Version string   `json:"version"`
+	URLs    []string `json:"urls"`
+}
+
+// resolveRepoChartURL fetches ref.URL's index.yaml and resolves ref.Name and
+// ref.Version to a concrete, absolute chart archive URL and the version it
+// points at.
+func resolveRepoChartURL(ctx context.Context, g Getter, ref RemoteReference) (chartURL, version string, err error) {
+	if ref.Name == "" {
+		return "", "", fmt.Errorf("remote reference is missing a chart name")
+	}
+
+	indexURL := strings.TrimSuffix(ref.URL, "/") + "/index.yaml"
+	r, err := g.Get(ctx, indexURL)
+	if err != nil {
+		return "", "", fmt.Errorf("error fetching repository index %s: %w", indexURL, err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading repository index %s: %w", indexURL, err)
+	}
+
+	var idx repoIndex
+	if err := yaml.Unmarshal(data, &idx); err != nil {
+		return "", "", fmt.Errorf("error parsing repository index %s: %w", indexURL, err)
+	}
+
+	entries, ok := idx.Entries[ref.Name]
+	if !ok || len(entries) == 0 {
+		return "", "", fmt.Errorf("chart %q not found in repository index %s", ref.Name, indexURL)
+	}
+
+	entry, err := selectRepoIndexEntry(entries, ref.Version)
+	if err != nil {
+		return "", "", fmt.Errorf("chart %q in repository index %s: %w", ref.Name, indexURL, err)
+	}
+	if len(entry.URLs) == 0 {
+		return "", "", fmt.Errorf("chart %q version %q has no archive URL in repository index %s", ref.Name, entry.Version, indexURL)
+	}
+
+	resolved, err := resolveChartURL(ref.URL, entry.URLs[0])
+	if err != nil {
+		return "", "", err
+	}
+
+	return resolved, entry.Version, nil
+}
+
+// selectRepoIndexEntry picks the entry matching constraint out of entries,
+// which are not assumed to be sorted in any particular order: every entry's
+// version is parsed and compared, rather than trusting entries[0] to be
+// newest. An empty constraint selects the highest non-prerelease version;
+// an entry whose version doesn't parse as semver is skipped.
+func selectRepoIndexEntry(entries []repoIndexEntry, constraint string) (repoIndexEntry, error) {
+	var c *semver.Constraints
+	if constraint != "" {
+		parsed, err := semver.NewConstraint(constraint)
+		if err != nil {
+			return repoIndexEntry{}, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+		c = parsed
+	}
+
+	var best *semver.Version
+	var bestEntry repoIndexEntry
+	for _, e := range entries {
+		v, err := semver.NewVersion(e.Version)
+		if err != nil {
+			continue
+		}
+		switch {
+		case c != nil && !c.Check(v):
+			continue
+		case c == nil && v.Prerelease() != "":
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best, bestEntry = v, e
+		}
+	}
+
+	if best == nil {
+		if constraint == "" {
+			return repoIndexEntry{}, fmt.Errorf("no stable version available")
+		}
+		return repoIndexEntry{}, fmt.Errorf("no version matches constraint %q", constraint)
+	}
+	return bestEntry, nil
+}
+
+// resolveChartURL resolves a chart archive URL found in a repository index
+// against the repository's own URL, the same way a browser resolves a
+// relative link: absolute archive URLs pass through unchanged, and
+// relative ones are joined to repoURL.
+func resolveChartURL(repoURL, chartURL string) (string, error) {
+	u, err := url.Parse(chartURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid chart URL %q: %w", chartURL, err)
+	}
+	if u.IsAbs() {
+		return chartURL, nil
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(repoURL, "/") + "/")
+	if err != nil {
+		return "", fmt.Errorf("invalid repository URL %q: %w", repoURL, err)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// OCIPuller pulls a chart artifact out of an OCI registry. It abstracts the
+// ORAS client so OCIBuilder can be tested and reused without pulling the
+// ORAS dependency into every caller.
+type OCIPuller interface {
+	Pull(ctx context.Context, ref string) (data []byte, digest string, err error)
+}
+
+// OCIBuilder builds a chart published to an OCI registry.
+type OCIBuilder struct {
+	// Puller pulls the chart artifact layer for a given oci:// reference.
+	Puller OCIPuller
+}
+
+// Build implements ChartBuilder.
+func (b OCIBuilder) Build(ctx context.Context, opts BuildOptions) (*BuildResult, error) {
+	ref, ok := opts.Reference.(RemoteReference)
+	if !ok {
+		return nil, fmt.Errorf("OCI builder requires a RemoteReference, got %T", opts.Reference)
+	}
+	if b.Puller == nil {
+		return nil, fmt.Errorf("OCI builder requires a Puller")
+	}
+
+	ociRef := strings.TrimPrefix(ref.URL, "oci://") + "/" + ref.Name
+	if ref.Version != "" {
+		ociRef += ":" + ref.Version
+	}
+
+	data, digest, err := b.Puller.Pull(ctx, ociRef)
+	if err != nil {
+		return nil, fmt.Errorf("error pulling %s: %w", ociRef, err)
+	}
+
+	path, err := writeTempArchive(ref.Name, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	result, err := finishBuild(c, path, c.Metadata.Version, opts)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	if digest != "" {
+		result.Digest = digest
+	}
+	return result, nil
+}
+
+// finishBuild merges opts.ValuesFiles over c's default values and assembles
+// the BuildResult shared by every ChartBuilder implementation.
+func finishBuild(c *chart.Chart, path, version string, opts BuildOptions) (*BuildResult, error) {
+	values := c.Values
+	for _, f := range opts.ValuesFiles {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading values file %s: %w", f, err)
+		}
+		overrides, err := LoadValues(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing values file %s: %w", f, err)
+		}
+		values = MergeMaps(values, overrides)
+	}
+
+	digest, err := digestFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BuildResult{
+		Chart:   c,
+		Path:    path,
+		Digest:  digest,
+		Version: version,
+		Values:  values,
+	}, nil
+}
+
+// digestFile returns the hex encoded SHA-256 digest of the file at path. A
+// path pointing at an unpacked directory (LocalBuilder's usual case) has no
+// single digest, so an empty string is returned instead of an error.
+func digestFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		fi, statErr := os.Stat(path)
+		if statErr == nil && fi.IsDir() {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		return "", nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeTempArchive copies r into a temporary .tgz file named after chart so
+// it can be handed to Load like any chart on disk.
+func writeTempArchive(name string, r io.Reader) (string, error) {
+	f, err := os.CreateTemp("", strings.ReplaceAll(name, "/", "_")+"-*.tgz")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file for %s: %w", name, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", fmt.Errorf("error writing temporary file for %s: %w", name, err)
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalBuilder(t *testing.T) {
+	b := LocalBuilder{}
+	result, err := b.Build(context.Background(), BuildOptions{
+		Reference: LocalReference{Path: "testdata/frobnitz"},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if got, want := result.Chart.Name(), "frobnitz"; got != want {
+		t.Errorf("Chart.Name() = %q, want %q", got, want)
+	}
+	if got, want := result.Version, "1.2.3"; got != want {
+		t.Errorf("Version = %q, want %q", got, want)
+	}
+	// LocalBuilder points Path straight at the input; there's no temp file
+	// for the caller to clean up.
+	if result.Path != "testdata/frobnitz" {
+		t.Errorf("Path = %q, want %q", result.Path, "testdata/frobnitz")
+	}
+}
+
+func TestLocalBuilderWrongReference(t *testing.T) {
+	b := LocalBuilder{}
+	if _, err := b.Build(context.Background(), BuildOptions{Reference: RemoteReference{}}); err == nil {
+		t.Fatal("Build() with a RemoteReference: expected an error, got nil")
+	}
+}
+
+func TestRemoteBuilder(t *testing.T) {
+	repoDir := t.TempDir()
+	writeTestArchive(t, filepath.Join(repoDir, "frobnitz-1.2.3.tgz"), "testdata/frobnitz", "frobnitz")
+	os.WriteFile(filepath.Join(repoDir, "index.yaml"), []byte(`apiVersion: v1
+entries:
+  frobnitz:
+    - version: 1.2.3
+      urls:
+        - frobnitz-1.2.3.tgz
+`), 0o644)
+
+	b := RemoteBuilder{Getter: FileGetter{}}
+	result, err := b.Build(context.Background(), BuildOptions{
+		Reference: RemoteReference{URL: "file://" + repoDir, Name: "frobnitz"},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	defer os.Remove(result.Path)
+
+	if got, want := result.Chart.Name(), "frobnitz"; got != want {
+		t.Errorf("Chart.Name() = %q, want %q", got, want)
+	}
+	if got, want := result.Version, "1.2.3"; got != want {
+		t.Errorf("Version = %q, want %q", got, want)
+	}
+	if result.Digest == "" {
+		t.Error("Digest is empty, want the archive's SHA-256 digest")
+	}
+	if _, err := os.Stat(result.Path); err != nil {
+		t.Errorf("BuildResult.Path %q does not exist: %v", result.Path, err)
+	}
+}
+
+func TestRemoteBuilderUnknownChart(t *testing.T) {
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, "index.yaml"), []byte("apiVersion: v1\nentries: {}\n"), 0o644)
+
+	b := RemoteBuilder{Getter: FileGetter{}}
+	_, err := b.Build(context.Background(), BuildOptions{
+		Reference: RemoteReference{URL: "file://" + repoDir, Name: "frobnitz"},
+	})
+	if err == nil {
+		t.Fatal("Build() for a chart missing from the index: expected an error, got nil")
+	}
+}
+
+func TestSelectRepoIndexEntry(t *testing.T) {
+	// Deliberately out of order: selectRepoIndexEntry must not rely on
+	// entries being sorted newest-first.
+	entries := []repoIndexEntry{
+		{Version: "1.0.0"},
+		{Version: "2.0.0-rc.1"},
+		{Version: "1.5.0"},
+	}
+
+	tests := []struct {
+		name       string
+		constraint string
+		want       string
+		wantErr    bool
+	}{
+		{name: "empty constraint picks highest stable", constraint: "", want: "1.5.0"},
+		{name: "exact version", constraint: "1.0.0", want: "1.0.0"},
+		{name: "range constraint", constraint: ">=1.0.0 <1.5.0", want: "1.0.0"},
+		{name: "prerelease only matches explicit constraint", constraint: "2.0.0-rc.1", want: "2.0.0-rc.1"},
+		{name: "no match", constraint: "^3.0.0", wantErr: true},
+		{name: "invalid constraint", constraint: "not-a-constraint!!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectRepoIndexEntry(entries, tt.constraint)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("selectRepoIndexEntry(%q) error = nil, want an error", tt.constraint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectRepoIndexEntry(%q) error = %v", tt.constraint, err)
+			}
+			if got.Version != tt.want {
+				t.Errorf("selectRepoIndexEntry(%q) = %q, want %q", tt.constraint, got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestOCIBuilder(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := OCIBuilder{Puller: fakeOCIPuller{data: data, digest: "sha256:deadbeef"}}
+	result, err := b.Build(context.Background(), BuildOptions{
+		Reference: RemoteReference{URL: "oci://registry.example.com/charts", Name: "frobnitz", Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	defer os.Remove(result.Path)
+
+	if got, want := result.Chart.Name(), "frobnitz"; got != want {
+		t.Errorf("Chart.Name() = %q, want %q", got, want)
+	}
+	if got, want := result.Digest, "sha256:deadbeef"; got != want {
+		t.Errorf("Digest = %q, want %q", got, want)
+	}
+}
+
+type fakeOCIPuller struct {
+	data   []byte
+	digest string
+}
+
+func (f fakeOCIPuller) Pull(_ context.Context, _ string) ([]byte, string, error) {
+	return f.data, f.digest, nil
+}
+
+// writeTestArchive packages dir into a gzipped tar at path, the way a real
+// "helm package" would, so builder tests have something for Load to read.
+// chartName is the archive's top-level directory, matching the name in
+// dir's Chart.yaml.
+func writeTestArchive(t *testing.T, path, dir, chartName string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name: chartName + "/" + filepath.ToSlash(rel),
+			Mode: 0o644,
+			Size: int64(len(data)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("error packaging test archive: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar writer: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("error closing gzip writer: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("error writing test archive %s: %v", path, err)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/internal/chart/v3"
+)
+
+// LoadOptions customizes LoadFilesWithOptions and VerifyingLoader.
+type LoadOptions struct {
+	// DependencyManager, when set, is consulted to fetch subchart
+	// dependencies declared in Chart.lock that have no entry at all under
+	// charts/. Leave nil to get LoadFiles' behavior, which loads whatever
+	// charts/ holds and does not report a dependency that is absent from it.
+	//
+	// This only detects dependencies that are entirely missing, not ones
+	// that are present but stale: chart.Dependency carries no per-dependency
+	// digest for the loader to compare against, so a charts/<name> that
+	// exists but no longer matches what Chart.lock expects is not
+	// refetched. A DependencyManager implementation is free to re-verify
+	// and refresh such entries itself if it has enough information to do
+	// so (e.g. by re-resolving the full lock against a repository mirror).
+	DependencyManager DependencyManager
+
+	// Keyring supplies the OpenPGP public keys trusted to sign charts. It
+	// is required when VerifyMode is anything other than VerifyNone.
+	Keyring io.Reader
+	// VerifyMode controls whether and how strictly a chart's provenance is
+	// verified. Defaults to VerifyNone.
+	VerifyMode VerifyMode
+}
+
+// DependencyManager resolves a chart's subchart dependencies as recorded in
+// its Chart.lock. Implementations are expected to fetch each dependency from
+// a local cache, a chart repository, or an OCI registry via a pluggable
+// Getter, and to verify the fetched bytes before returning, refusing to
+// proceed on a mismatch. lock holds no digest per dependency: Lock.Digest
+// covers the dependency list as a whole. Build is invoked once, with the
+// whole lock, when reconcileDependencies finds at least one dependency with
+// no charts/ entry at all; everything it returns is appended to the files.
+// See LoadOptions.DependencyManager for what that does and doesn't cover.
+type DependencyManager interface {
+	// Build returns the buffered files for chart's dependencies, rooted at
+	// "charts/<name>/..." (or "charts/<name>.tgz" for a packaged
+	// dependency) exactly as they would appear in the files passed to
+	// LoadFiles.
+	Build(ctx context.Context, chart *chart.Chart, lock *chart.Lock) ([]*BufferedFile, error)
+}
+
+// reconcileDependencies fills in any dependency declared in c.Lock that has
+// no entry at all under files' charts/ prefix by asking dm to fetch it,
+// returning files with the fetched entries appended. It does not detect a
+// dependency that is present but stale; see LoadOptions.DependencyManager.
+func reconcileDependencies(ctx context.Context, c *chart.Chart, files []*BufferedFile, dm DependencyManager) ([]*BufferedFile, error) {
+	lockFile := findBufferedFile(files, "Chart.lock")
+	if lockFile == nil {
+		return files, nil
+	}
+
+	lock := new(chart.Lock)
+	if err := yaml.Unmarshal(lockFile.Data, lock); err != nil {
+		return files, fmt.Errorf("cannot load Chart.lock: %w", err)
+	}
+
+	if len(missingDependencies(lock, files)) == 0 {
+		return files, nil
+	}
+
+	fetched, err := dm.Build(ctx, c, lock)
+	if err != nil {
+		return files, fmt.Errorf("error reconciling dependencies for %s: %w", c.Name(), err)
+	}
+	return append(files, fetched...), nil
+}
+
+// missingDependencies returns the names, from lock.Dependencies, that have
+// no corresponding entry under charts/ in files. It checks presence by name
+// only, not by digest: Chart.lock records no per-dependency digest for it
+// to compare against, so a present-but-stale charts/<name> is not reported
+// as missing.
+func missingDependencies(lock *chart.Lock, files []*BufferedFile) []string {
+	present := make(map[string]bool)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, "charts/") {
+			continue
+		}
+		fname := strings.TrimPrefix(f.Name, "charts/")
+		cname := strings.SplitN(fname, "/", 2)[0]
+		present[strings.TrimSuffix(cname, ".tgz")] = true
+	}
+
+	var missing []string
+	for _, d := range lock.Dependencies {
+		if !present[d.Name] {
+			missing = append(missing, d.Name)
+		}
+	}
+	return missing
+}
+
+// findBufferedFile returns the first file in files named name, or nil.
+func findBufferedFile(files []*BufferedFile, name string) *BufferedFile {
+	for _, f := range files {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
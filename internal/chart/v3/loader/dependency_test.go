@@ -0,0 +1,164 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"context"
+	"testing"
+
+	chart "helm.sh/helm/v4/internal/chart/v3"
+)
+
+// fakeDependencyManager returns a fixed set of files regardless of what
+// chart.lock asks for, so tests can assert on what reconcileDependencies
+// passes it rather than on any real fetch logic.
+type fakeDependencyManager struct {
+	files  []*BufferedFile
+	err    error
+	lock   *chart.Lock
+	called bool
+}
+
+func (f *fakeDependencyManager) Build(_ context.Context, _ *chart.Chart, lock *chart.Lock) ([]*BufferedFile, error) {
+	f.called = true
+	f.lock = lock
+	return f.files, f.err
+}
+
+func chartLockFile(t *testing.T) *BufferedFile {
+	t.Helper()
+	return &BufferedFile{Name: "Chart.lock", Data: []byte(`dependencies:
+  - name: subchart
+    version: "1.0.0"
+    repository: https://example.com/charts
+digest: sha256:deadbeef
+`)}
+}
+
+func TestReconcileDependenciesFillsInMissing(t *testing.T) {
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "frobnitz"}}
+	files := []*BufferedFile{chartLockFile(t)}
+
+	fetched := &BufferedFile{Name: "charts/subchart/Chart.yaml", Data: []byte("name: subchart\nversion: 1.0.0\n")}
+	dm := &fakeDependencyManager{files: []*BufferedFile{fetched}}
+
+	got, err := reconcileDependencies(context.Background(), c, files, dm)
+	if err != nil {
+		t.Fatalf("reconcileDependencies() error = %v", err)
+	}
+	if !dm.called {
+		t.Fatal("DependencyManager.Build was not called for a missing dependency")
+	}
+	if findBufferedFile(got, fetched.Name) == nil {
+		t.Errorf("reconciled files do not contain %s", fetched.Name)
+	}
+	if len(dm.lock.Dependencies) != 1 || dm.lock.Dependencies[0].Name != "subchart" {
+		t.Errorf("Build was called with lock %+v, want one dependency named subchart", dm.lock)
+	}
+}
+
+func TestReconcileDependenciesSkipsWhenNothingMissing(t *testing.T) {
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "frobnitz"}}
+	files := []*BufferedFile{
+		chartLockFile(t),
+		{Name: "charts/subchart/Chart.yaml", Data: []byte("name: subchart\nversion: 1.0.0\n")},
+	}
+
+	dm := &fakeDependencyManager{}
+	got, err := reconcileDependencies(context.Background(), c, files, dm)
+	if err != nil {
+		t.Fatalf("reconcileDependencies() error = %v", err)
+	}
+	if dm.called {
+		t.Error("DependencyManager.Build was called even though charts/subchart is already present")
+	}
+	if len(got) != len(files) {
+		t.Errorf("len(files) = %d, want %d (no files added)", len(got), len(files))
+	}
+}
+
+func TestReconcileDependenciesNoLockFile(t *testing.T) {
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "frobnitz"}}
+	files := []*BufferedFile{{Name: "values.yaml", Data: []byte("favoriteDrink: coffee\n")}}
+
+	dm := &fakeDependencyManager{}
+	got, err := reconcileDependencies(context.Background(), c, files, dm)
+	if err != nil {
+		t.Fatalf("reconcileDependencies() error = %v", err)
+	}
+	if dm.called {
+		t.Error("DependencyManager.Build was called for a chart with no Chart.lock")
+	}
+	if len(got) != len(files) {
+		t.Errorf("len(files) = %d, want %d (no files added)", len(got), len(files))
+	}
+}
+
+func TestLoadFilesWithOptionsNilDependencyManagerIsNoOp(t *testing.T) {
+	files := []*BufferedFile{
+		{Name: "Chart.yaml", Data: []byte("name: frobnitz\nversion: 1.2.3\n")},
+		chartLockFile(t),
+	}
+
+	// With no DependencyManager, a missing charts/subchart is simply left
+	// missing: LoadFilesWithOptions does not error on an incomplete Chart.lock
+	// by itself, it only fails to produce the dependency in Dependencies().
+	c, err := LoadFilesWithOptions(context.Background(), files, LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadFilesWithOptions() error = %v", err)
+	}
+	if len(c.Dependencies()) != 0 {
+		t.Errorf("len(Dependencies()) = %d, want 0", len(c.Dependencies()))
+	}
+}
+
+func TestMissingDependencies(t *testing.T) {
+	lock := &chart.Lock{Dependencies: []*chart.Dependency{
+		{Name: "present"},
+		{Name: "absent"},
+	}}
+	files := []*BufferedFile{
+		{Name: "charts/present/Chart.yaml", Data: nil},
+	}
+
+	got := missingDependencies(lock, files)
+	if len(got) != 1 || got[0] != "absent" {
+		t.Errorf("missingDependencies() = %v, want [absent]", got)
+	}
+}
+
+// TestMissingDependenciesIgnoresStaleContent locks in the documented scope
+// of LoadOptions.DependencyManager: a charts/<name> that is present but no
+// longer matches what Chart.lock expects is not reported as missing, since
+// chart.Dependency carries no per-dependency digest to compare against.
+func TestMissingDependenciesIgnoresStaleContent(t *testing.T) {
+	lock := &chart.Lock{
+		Digest: "sha256:expected",
+		Dependencies: []*chart.Dependency{
+			{Name: "subchart", Version: "1.0.0"},
+		},
+	}
+	files := []*BufferedFile{
+		// Present under charts/, but its content has nothing to do with
+		// what lock.Digest was computed from.
+		{Name: "charts/subchart/Chart.yaml", Data: []byte("name: subchart\nversion: 0.0.1-stale\n")},
+	}
+
+	if got := missingDependencies(lock, files); len(got) != 0 {
+		t.Errorf("missingDependencies() = %v, want none (a stale-but-present dependency is not \"missing\")", got)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/ignore"
+
+	chart "helm.sh/helm/v4/internal/chart/v3"
+)
+
+// utf8bom is the byte order mark some editors prepend to UTF-8 files. Chart
+// files are read as-is, so strip it if present.
+var utf8bom = []byte{0xEF, 0xBB, 0xBF}
+
+// LoadFS returns a new ChartLoader appropriate for the given chart name,
+// resolved against fsys rather than the host filesystem. This allows charts
+// to be loaded out of an embed.FS, an in-memory tree, or any other fs.FS
+// implementation without first materializing them to disk.
+func LoadFS(fsys fs.FS, name string) (ChartLoader, error) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return FSLoader(fsys, name), nil
+	}
+	return fsFileLoader{fsys: fsys, name: name}, nil
+}
+
+// FSLoader returns a ChartLoader that reads a chart rooted at root within
+// fsys. .helmignore handling and subchart recursion work the same as
+// DirLoader. Symlinks are not followed: a symlink anywhere in the tree fails
+// the load.
+func FSLoader(fsys fs.FS, root string) ChartLoader {
+	return fsDirLoader{fsys: fsys, root: root}
+}
+
+type fsDirLoader struct {
+	fsys fs.FS
+	root string
+}
+
+func (f fsDirLoader) Load() (*chart.Chart, error) {
+	return LoadFilesFromFS(f.fsys, f.root)
+}
+
+type fsFileLoader struct {
+	fsys fs.FS
+	name string
+}
+
+func (f fsFileLoader) Load() (*chart.Chart, error) {
+	file, err := f.fsys.Open(f.name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadArchive(file)
+}
+
+// LoadFilesFromFS walks fsys starting at root, honoring a root-level
+// .helmignore, and loads the result into an in-memory chart. It is the
+// fs.FS counterpart of LoadDir and does the heavy lifting for FSLoader.
+func LoadFilesFromFS(fsys fs.FS, root string) (*chart.Chart, error) {
+	root = path.Clean(root)
+
+	rules := ignore.Empty()
+	if data, err := fs.ReadFile(fsys, path.Join(root, ignore.HelmIgnore)); err == nil {
+		r, err := ignore.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		rules = r
+	}
+	rules.AddDefaults()
+
+	var files []*BufferedFile
+	walk := func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		n := p
+		if root != "." {
+			n = strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		}
+		if n == "" || n == "." {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("unable to stat %s: %w", p, err)
+		}
+
+		if d.IsDir() {
+			if rules.Ignore(n, fi) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		// fs.FS gives no portable way to resolve a symlink's target safely
+		// (unlike sympath.Walk on the host filesystem), so rather than risk
+		// walking outside of root we refuse to follow them.
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("cannot load irregular file %s: symlinks are not supported when loading from an fs.FS", p)
+		}
+
+		if rules.Ignore(n, fi) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", p, err)
+		}
+		data = bytes.TrimPrefix(data, utf8bom)
+
+		files = append(files, &BufferedFile{Name: n, Data: data})
+		return nil
+	}
+
+	if err := fs.WalkDir(fsys, root, walk); err != nil {
+		return &chart.Chart{}, err
+	}
+
+	return LoadFiles(files)
+}
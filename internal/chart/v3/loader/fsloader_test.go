@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFilesFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"frobnitz/Chart.yaml":                {Data: []byte("name: frobnitz\nversion: 1.2.3\n")},
+		"frobnitz/values.yaml":               {Data: []byte("favoriteDrink: coffee\n")},
+		"frobnitz/templates/placeholder.txt": {Data: []byte("hello: {{ .Values.favoriteDrink }}\n")},
+	}
+
+	c, err := LoadFilesFromFS(fsys, "frobnitz")
+	if err != nil {
+		t.Fatalf("LoadFilesFromFS() error = %v", err)
+	}
+	if got, want := c.Name(), "frobnitz"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+	if len(c.Templates) != 1 {
+		t.Fatalf("len(Templates) = %d, want 1", len(c.Templates))
+	}
+}
+
+func TestLoadFSDispatchesToFSLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"frobnitz/Chart.yaml": {Data: []byte("name: frobnitz\nversion: 1.2.3\n")},
+	}
+
+	l, err := LoadFS(fsys, "frobnitz")
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	if _, ok := l.(fsDirLoader); !ok {
+		t.Fatalf("LoadFS() for a directory returned %T, want fsDirLoader", l)
+	}
+}
+
+func TestLoadFilesFromFSHonorsHelmIgnore(t *testing.T) {
+	fsys := fstest.MapFS{
+		"frobnitz/Chart.yaml":   {Data: []byte("name: frobnitz\nversion: 1.2.3\n")},
+		"frobnitz/.helmignore":  {Data: []byte("secrets.yaml\n")},
+		"frobnitz/secrets.yaml": {Data: []byte("password: hunter2\n")},
+		"frobnitz/values.yaml":  {Data: []byte("favoriteDrink: coffee\n")},
+	}
+
+	c, err := LoadFilesFromFS(fsys, "frobnitz")
+	if err != nil {
+		t.Fatalf("LoadFilesFromFS() error = %v", err)
+	}
+	for _, f := range c.Files {
+		if f.Name == "secrets.yaml" {
+			t.Fatalf("secrets.yaml was not ignored, got Files = %+v", c.Files)
+		}
+	}
+}
+
+func TestLoadFilesFromFSRefusesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/Chart.yaml", []byte("name: frobnitz\nversion: 1.2.3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dir+"/Chart.yaml", dir+"/values.yaml"); err != nil {
+		t.Skipf("symlinks not supported on this platform: %v", err)
+	}
+
+	_, err := LoadFilesFromFS(os.DirFS(dir), ".")
+	if err == nil {
+		t.Fatal("LoadFilesFromFS() with a symlinked file: expected an error, got nil")
+	}
+}
@@ -19,6 +19,7 @@ package loader
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -73,8 +74,17 @@ type BufferedFile struct {
 
 // LoadFiles loads from in-memory files.
 func LoadFiles(files []*BufferedFile) (*chart.Chart, error) {
+	return LoadFilesWithOptions(context.Background(), files, LoadOptions{})
+}
+
+// LoadFilesWithOptions loads from in-memory files like LoadFiles, but allows
+// the caller to opt into extra behavior via opts. Currently this is limited
+// to reconciling missing subchart dependencies through
+// LoadOptions.DependencyManager; see its doc comment for details, including
+// the scope limitation that a present-but-stale charts/ entry is left alone
+// rather than refetched.
+func LoadFilesWithOptions(ctx context.Context, files []*BufferedFile, opts LoadOptions) (*chart.Chart, error) {
 	c := new(chart.Chart)
-	subcharts := make(map[string][]*BufferedFile)
 
 	// do not rely on assumed ordering of files in the chart and crash
 	// if Chart.yaml was not coming early enough to initialize metadata
@@ -95,6 +105,17 @@ func LoadFiles(files []*BufferedFile) (*chart.Chart, error) {
 			}
 		}
 	}
+
+	if opts.DependencyManager != nil {
+		reconciled, err := reconcileDependencies(ctx, c, files, opts.DependencyManager)
+		if err != nil {
+			return c, err
+		}
+		files = reconciled
+	}
+
+	subcharts := make(map[string][]*BufferedFile)
+
 	for _, f := range files {
 		switch {
 		case f.Name == "Chart.yaml":
@@ -0,0 +1,314 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck
+	"golang.org/x/crypto/openpgp/clearsign"
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/internal/chart/v3"
+)
+
+// VerifyMode controls how strictly VerifyingLoader treats chart provenance.
+// See LoadOptions.VerifyMode.
+type VerifyMode int
+
+const (
+	// VerifyNone skips provenance verification entirely.
+	VerifyNone VerifyMode = iota
+	// VerifyIfPresent verifies provenance when a .prov file is available,
+	// but does not require one.
+	VerifyIfPresent
+	// VerifyAlways requires a valid, verified .prov file; a missing or
+	// invalid one fails the load.
+	VerifyAlways
+)
+
+// VerificationErrorKind distinguishes the ways provenance verification can
+// fail.
+type VerificationErrorKind int
+
+const (
+	// VerificationErrorMissingProvenance means no .prov file was found.
+	VerificationErrorMissingProvenance VerificationErrorKind = iota
+	// VerificationErrorBadSignature means the .prov file's OpenPGP
+	// signature could not be verified: no keyring was supplied, the file
+	// holds no clearsigned message or an unparseable body, or the signature
+	// did not verify against the supplied keyring.
+	VerificationErrorBadSignature
+	// VerificationErrorDigestMismatch means the signature verified, but the
+	// packaged chart's digest doesn't match the one recorded in the
+	// provenance.
+	VerificationErrorDigestMismatch
+)
+
+// VerificationError reports why verifying a chart's provenance failed.
+type VerificationError struct {
+	Kind VerificationErrorKind
+	Path string
+	Err  error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("provenance verification failed for %s: %s", e.Path, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error { return e.Err }
+
+// VerifyingLoader loads a packaged chart archive and, according to
+// Options.VerifyMode, verifies its sibling .prov file against
+// Options.Keyring. Provenance is a property of a packaged chart, so unlike
+// FSLoader and DirLoader, VerifyingLoader always reads a .tgz rather than an
+// unpacked directory.
+type VerifyingLoader struct {
+	// ArchivePath is the packaged chart (.tgz) to load. ArchivePath+".prov"
+	// is where its provenance file, if any, is expected to live.
+	ArchivePath string
+	// Options supplies the keyring and verify mode. DependencyManager is
+	// ignored; VerifyingLoader only loads a single archive.
+	Options LoadOptions
+}
+
+// Load implements ChartLoader.
+func (v VerifyingLoader) Load() (*chart.Chart, error) {
+	data, err := os.ReadFile(v.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Options.VerifyMode == VerifyNone {
+		return c, nil
+	}
+	if v.Options.Keyring == nil {
+		return c, &VerificationError{Kind: VerificationErrorBadSignature, Path: v.ArchivePath, Err: errors.New("no keyring supplied for verification")}
+	}
+
+	// Buffer the keyring once: verifyProvenance drains whatever reader it is
+	// given, and both the top-level chart and every signed subchart below
+	// need to read it.
+	keyringData, err := io.ReadAll(v.Options.Keyring)
+	if err != nil {
+		return c, fmt.Errorf("error reading keyring: %w", err)
+	}
+
+	prov, err := verifyChartArchive(filepath.Base(v.ArchivePath), data, v.ArchivePath+".prov", bytes.NewReader(keyringData))
+	if err != nil {
+		var verr *VerificationError
+		if !errors.As(err, &verr) || verr.Kind != VerificationErrorMissingProvenance || v.Options.VerifyMode != VerifyIfPresent {
+			return c, err
+		}
+	} else {
+		c.Provenance = prov
+	}
+
+	if err := verifySubchartProvenance(c, data, keyringData, v.Options.VerifyMode); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// verifySubchartProvenance re-reads archiveData looking for charts/*.tgz
+// entries with a sibling charts/*.tgz.prov, verifies each pair, and attaches
+// the result to the matching dependency in c.Dependencies().
+//
+// It iterates c.Dependencies() rather than the archive entries themselves:
+// a packaged subchart is named "charts/<name>-<version>.tgz", so matching
+// entries back to a dependency by name requires knowing the dependency's own
+// version, not just stripping ".tgz" off the archive's file name.
+func verifySubchartProvenance(c *chart.Chart, archiveData, keyringData []byte, mode VerifyMode) error {
+	tgzs, provs, err := collectSubchartArchives(archiveData)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range c.Dependencies() {
+		name, data, ok := findSubchartArchive(dep, tgzs)
+		if !ok {
+			if mode == VerifyAlways {
+				return &VerificationError{Kind: VerificationErrorMissingProvenance, Path: dep.Name(), Err: fmt.Errorf("no packaged archive found for subchart %q", dep.Name())}
+			}
+			continue
+		}
+
+		provData, ok := provs[name+".prov"]
+		if !ok {
+			if mode == VerifyAlways {
+				return &VerificationError{Kind: VerificationErrorMissingProvenance, Path: name, Err: fmt.Errorf("no provenance file found")}
+			}
+			continue
+		}
+
+		prov, err := verifyProvenance(filepath.Base(name), data, provData, bytes.NewReader(keyringData))
+		if err != nil {
+			return err
+		}
+		dep.Provenance = prov
+	}
+
+	return nil
+}
+
+// findSubchartArchive returns dep's packaged archive among tgzs, which is
+// keyed by its "charts/<name>[-<version>].tgz" path within the parent
+// archive. Both the bare and version-suffixed forms are tried, since a
+// subchart may be vendored either way.
+func findSubchartArchive(dep *chart.Chart, tgzs map[string][]byte) (name string, data []byte, ok bool) {
+	candidates := []string{"charts/" + dep.Name() + ".tgz"}
+	if dep.Metadata != nil && dep.Metadata.Version != "" {
+		candidates = append(candidates, "charts/"+dep.Name()+"-"+dep.Metadata.Version+".tgz")
+	}
+	for _, c := range candidates {
+		if data, ok := tgzs[c]; ok {
+			return c, data, true
+		}
+	}
+	return "", nil, false
+}
+
+// collectSubchartArchives walks a packaged chart's tar+gzip contents and
+// returns the raw bytes of every charts/*.tgz and charts/*.tgz.prov entry,
+// keyed by their path within the archive.
+func collectSubchartArchives(archiveData []byte) (tgzs, provs map[string][]byte, err error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(archiveData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading chart archive: %w", err)
+	}
+	defer gzr.Close()
+
+	tgzs = map[string][]byte{}
+	provs = map[string][]byte{}
+
+	tr := tar.NewReader(gzr)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading chart archive: %w", err)
+		}
+
+		name := strings.TrimPrefix(hd.Name, "./")
+		parts := strings.SplitN(name, "/", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[1], "charts/") {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(name, ".tgz"):
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			tgzs[parts[1]] = data
+		case strings.HasSuffix(name, ".tgz.prov"):
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, err
+			}
+			provs[parts[1]] = data
+		}
+	}
+
+	return tgzs, provs, nil
+}
+
+// verifyChartArchive reads provPath and delegates to verifyProvenance.
+func verifyChartArchive(name string, archiveData []byte, provPath string, keyring io.Reader) (*chart.Provenance, error) {
+	provData, err := os.ReadFile(provPath)
+	if err != nil {
+		return nil, &VerificationError{Kind: VerificationErrorMissingProvenance, Path: provPath, Err: err}
+	}
+	return verifyProvenance(name, archiveData, provData, keyring)
+}
+
+// provenanceBody is the part of a clearsigned .prov file this package cares
+// about: the digest recorded for each packaged file it covers.
+type provenanceBody struct {
+	Files map[string]string `json:"files"`
+}
+
+// verifyProvenance checks provData's clearsigned OpenPGP signature against
+// keyring, then confirms it records archiveData's SHA-256 digest under name.
+func verifyProvenance(name string, archiveData, provData []byte, keyring io.Reader) (*chart.Provenance, error) {
+	block, _ := clearsign.Decode(provData)
+	if block == nil {
+		return nil, &VerificationError{Kind: VerificationErrorBadSignature, Path: name, Err: errors.New("no clearsigned message found in provenance file")}
+	}
+
+	keys, err := openpgp.ReadArmoredKeyRing(keyring)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keyring: %w", err)
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(keys, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body)
+	if err != nil {
+		return nil, &VerificationError{Kind: VerificationErrorBadSignature, Path: name, Err: err}
+	}
+
+	var body provenanceBody
+	if err := yaml.Unmarshal(block.Plaintext, &body); err != nil {
+		return nil, &VerificationError{Kind: VerificationErrorBadSignature, Path: name, Err: fmt.Errorf("cannot parse provenance body: %w", err)}
+	}
+
+	want, ok := body.Files[name]
+	if !ok {
+		return nil, &VerificationError{Kind: VerificationErrorDigestMismatch, Path: name, Err: fmt.Errorf("provenance does not list a digest for %s", name)}
+	}
+
+	sum := sha256.Sum256(archiveData)
+	got := "sha256:" + hex.EncodeToString(sum[:])
+	if got != want {
+		return nil, &VerificationError{Kind: VerificationErrorDigestMismatch, Path: name, Err: fmt.Errorf("computed digest %s does not match provenance digest %s", got, want)}
+	}
+
+	return &chart.Provenance{
+		Signer:   primaryIdentity(signer),
+		KeyID:    signer.PrimaryKey.KeyIdString(),
+		Verified: true,
+		Digest:   got,
+	}, nil
+}
+
+// primaryIdentity returns the name of one of the identities attached to e,
+// which for a chart signing key is ordinarily the only one. When e has
+// several, which one is returned is not defined.
+func primaryIdentity(e *openpgp.Entity) string {
+	for name := range e.Identities {
+		return name
+	}
+	return ""
+}
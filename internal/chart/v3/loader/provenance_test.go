@@ -0,0 +1,311 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+func TestVerifyingLoaderValid(t *testing.T) {
+	signer := generateTestSigner(t)
+
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+	signTestArchive(t, signer, archivePath)
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options: LoadOptions{
+			Keyring:    bytes.NewReader(armoredTestPublicKey(t, signer)),
+			VerifyMode: VerifyAlways,
+		},
+	}
+
+	c, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Provenance == nil || !c.Provenance.Verified {
+		t.Fatal("Provenance.Verified = false, want true")
+	}
+	if c.Provenance.Signer == "" {
+		t.Error("Provenance.Signer is empty")
+	}
+}
+
+func TestVerifyingLoaderBadSignature(t *testing.T) {
+	signer := generateTestSigner(t)
+	other := generateTestSigner(t)
+
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+	signTestArchive(t, other, archivePath)
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options: LoadOptions{
+			// signed by other, but the keyring only trusts signer.
+			Keyring:    bytes.NewReader(armoredTestPublicKey(t, signer)),
+			VerifyMode: VerifyAlways,
+		},
+	}
+
+	_, err := l.Load()
+	assertVerificationErrorKind(t, err, VerificationErrorBadSignature)
+}
+
+func TestVerifyingLoaderDigestMismatch(t *testing.T) {
+	signer := generateTestSigner(t)
+
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+
+	prov := signProvenanceBody(t, signer, "frobnitz-1.2.3.tgz", "sha256:"+hex.EncodeToString(make([]byte, sha256.Size)))
+	if err := os.WriteFile(archivePath+".prov", prov, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options: LoadOptions{
+			Keyring:    bytes.NewReader(armoredTestPublicKey(t, signer)),
+			VerifyMode: VerifyAlways,
+		},
+	}
+
+	_, err := l.Load()
+	assertVerificationErrorKind(t, err, VerificationErrorDigestMismatch)
+}
+
+func TestVerifyingLoaderMissingProvenanceIfPresent(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options: LoadOptions{
+			Keyring:    bytes.NewReader(armoredTestPublicKey(t, generateTestSigner(t))),
+			VerifyMode: VerifyIfPresent,
+		},
+	}
+
+	c, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Provenance != nil {
+		t.Errorf("Provenance = %+v, want nil", c.Provenance)
+	}
+}
+
+func TestVerifyingLoaderMissingProvenanceAlways(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options: LoadOptions{
+			Keyring:    bytes.NewReader(armoredTestPublicKey(t, generateTestSigner(t))),
+			VerifyMode: VerifyAlways,
+		},
+	}
+
+	_, err := l.Load()
+	assertVerificationErrorKind(t, err, VerificationErrorMissingProvenance)
+}
+
+func TestVerifyingLoaderNilKeyring(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, "testdata/frobnitz", "frobnitz")
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options:     LoadOptions{VerifyMode: VerifyAlways},
+	}
+
+	_, err := l.Load()
+	assertVerificationErrorKind(t, err, VerificationErrorBadSignature)
+}
+
+func TestVerifyingLoaderSubchart(t *testing.T) {
+	signer := generateTestSigner(t)
+	dir := t.TempDir()
+
+	subchartPath := filepath.Join(dir, "subchart-1.0.0.tgz")
+	writeTestArchive(t, subchartPath, "testdata/subchart", "subchart")
+	signTestArchive(t, signer, subchartPath)
+
+	parentSrc := filepath.Join(dir, "frobnitz-src")
+	copyDir(t, "testdata/frobnitz", parentSrc)
+	copyFile(t, subchartPath, filepath.Join(parentSrc, "charts", "subchart-1.0.0.tgz"))
+	copyFile(t, subchartPath+".prov", filepath.Join(parentSrc, "charts", "subchart-1.0.0.tgz.prov"))
+
+	archivePath := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	writeTestArchive(t, archivePath, parentSrc, "frobnitz")
+	signTestArchive(t, signer, archivePath)
+
+	l := VerifyingLoader{
+		ArchivePath: archivePath,
+		Options: LoadOptions{
+			Keyring:    bytes.NewReader(armoredTestPublicKey(t, signer)),
+			VerifyMode: VerifyAlways,
+		},
+	}
+
+	c, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	deps := c.Dependencies()
+	if len(deps) != 1 {
+		t.Fatalf("len(Dependencies()) = %d, want 1", len(deps))
+	}
+	if deps[0].Provenance == nil || !deps[0].Provenance.Verified {
+		t.Errorf("subchart Provenance.Verified = false, want true")
+	}
+}
+
+// assertVerificationErrorKind fails the test unless err is a
+// *VerificationError of the given kind.
+func assertVerificationErrorKind(t *testing.T, err error, kind VerificationErrorKind) {
+	t.Helper()
+	var verr *VerificationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want a *VerificationError", err)
+	}
+	if verr.Kind != kind {
+		t.Errorf("VerificationError.Kind = %v, want %v", verr.Kind, kind)
+	}
+}
+
+// generateTestSigner creates a throwaway OpenPGP identity for signing test
+// provenance files.
+func generateTestSigner(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity("Test Signer", "", "test-signer@example.com", nil)
+	if err != nil {
+		t.Fatalf("error generating test signing key: %v", err)
+	}
+	return e
+}
+
+// armoredTestPublicKey serializes signer's public key as the armored keyring
+// VerifyingLoader expects.
+func armoredTestPublicKey(t *testing.T, signer *openpgp.Entity) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// signTestArchive reads archivePath, computes its digest, and writes a
+// clearsigned archivePath+".prov" asserting that digest for it.
+func signTestArchive(t *testing.T, signer *openpgp.Entity, archivePath string) {
+	t.Helper()
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	prov := signProvenanceBody(t, signer, filepath.Base(archivePath), digest)
+	if err := os.WriteFile(archivePath+".prov", prov, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// signProvenanceBody clearsigns a minimal provenance document recording
+// digest for name.
+func signProvenanceBody(t *testing.T, signer *openpgp.Entity, name, digest string) []byte {
+	t.Helper()
+	plaintext := fmt.Sprintf("files:\n  %s: %s\n", name, digest)
+
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, signer.PrivateKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// copyFile copies src to dst, creating dst's parent directory if needed.
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// copyDir recursively copies src into dst.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		copyFile(t, p, filepath.Join(dst, rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import "time"
+
+// Dependency describes a chart upon which another chart depends. Dependency
+// is used both in Chart.yaml's dependencies list and in Chart.lock.
+type Dependency struct {
+	// Name is the name of the dependency.
+	//
+	// This must mach the name in the dependency's Chart.yaml.
+	Name string `json:"name"`
+	// Version is the version (range) of the dependency.
+	Version string `json:"version,omitempty"`
+	// Repository is the repository URL the dependency should be resolved from.
+	Repository string `json:"repository"`
+	// Condition is a yaml path that resolves to a boolean, used for enabling/disabling charts.
+	Condition string `json:"condition,omitempty"`
+	// Tags can be used to group charts for enabling/disabling together.
+	Tags []string `json:"tags,omitempty"`
+	// Enabled bool determines if this dependency is loaded/built.
+	Enabled bool `json:"enabled,omitempty"`
+	// ImportValues holds the mapping of source values to parent key to be imported.
+	ImportValues []interface{} `json:"import-values,omitempty"`
+	// Alias usage is used to rename the top-level name of the chart.
+	Alias string `json:"alias,omitempty"`
+}
+
+// Lock is the contents of a Chart.lock file, generated by resolving
+// Chart.yaml's dependencies against a set of repositories.
+type Lock struct {
+	// Generated is the time this lock file was generated.
+	Generated time.Time `json:"generated"`
+	// Digest is a hash of the dependencies in Chart.yaml that produced this lock.
+	Digest string `json:"digest"`
+	// Dependencies is the list of dependencies that this lock file has resolved.
+	Dependencies []*Dependency `json:"dependencies"`
+}
@@ -0,0 +1,92 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import "errors"
+
+// Supported apiVersion values.
+const (
+	APIVersionV1 = "v1"
+	APIVersionV2 = "v2"
+	APIVersionV3 = "v3"
+)
+
+// ErrMetadataMissing is returned by Chart.Validate when the chart has no
+// Chart.yaml at all.
+var ErrMetadataMissing = errors.New("chart metadata (Chart.yaml) is missing")
+
+// Maintainer describes a chart maintainer.
+type Maintainer struct {
+	// Name is the maintainer's name.
+	Name string `json:"name,omitempty"`
+	// Email is the maintainer's email.
+	Email string `json:"email,omitempty"`
+	// URL is a URL for the maintainer.
+	URL string `json:"url,omitempty"`
+}
+
+// Metadata is the contents of a Chart.yaml file.
+type Metadata struct {
+	// Name is the name of the chart.
+	Name string `json:"name,omitempty"`
+	// Home is the URL of this project's home page.
+	Home string `json:"home,omitempty"`
+	// Sources is the URL to the source code of this chart.
+	Sources []string `json:"sources,omitempty"`
+	// Version is a SemVer 2 version for this chart.
+	Version string `json:"version,omitempty"`
+	// Description is a one-sentence description of this chart.
+	Description string `json:"description,omitempty"`
+	// Keywords are a list of keywords about this chart.
+	Keywords []string `json:"keywords,omitempty"`
+	// Maintainers is a list of maintainers for this chart.
+	Maintainers []*Maintainer `json:"maintainers,omitempty"`
+	// Icon is a URL to an SVG or PNG image to use as an icon.
+	Icon string `json:"icon,omitempty"`
+	// APIVersion is the chart API version.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Condition is the name of the value that enables/disables this chart.
+	Condition string `json:"condition,omitempty"`
+	// Tags are tags that can be used to group charts together.
+	Tags string `json:"tags,omitempty"`
+	// AppVersion is the version of the application this chart deploys.
+	AppVersion string `json:"appVersion,omitempty"`
+	// Deprecated marks this chart as deprecated.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// Annotations are additional mappings uninterpreted by Helm.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// KubeVersion is a SemVer constraint specifying the compatible Kubernetes versions.
+	KubeVersion string `json:"kubeVersion,omitempty"`
+	// Dependencies are a list of dependencies for this chart.
+	Dependencies []*Dependency `json:"dependencies,omitempty"`
+	// Type specifies the chart type: application or library.
+	Type string `json:"type,omitempty"`
+}
+
+// Validate checks the metadata for required fields.
+func (md *Metadata) Validate() error {
+	if md == nil {
+		return ErrMetadataMissing
+	}
+	if md.Name == "" {
+		return errors.New("chart.metadata.name is required")
+	}
+	if md.APIVersion == "" {
+		return errors.New("chart.metadata.apiVersion is required")
+	}
+	return nil
+}
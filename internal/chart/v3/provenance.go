@@ -0,0 +1,33 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+// Provenance records the outcome of verifying a chart's .prov file against a
+// keyring, as attached to Chart.Provenance (and to a dependency's Provenance
+// field) by loader.VerifyingLoader.
+type Provenance struct {
+	// Signer is the identity attached to the OpenPGP key that signed the
+	// chart, e.g. "Jane Doe <jane@example.com>".
+	Signer string
+	// KeyID is the hex-encoded key ID of the signing key.
+	KeyID string
+	// Verified is true once the signature and the packaged chart's digest
+	// have both been checked successfully.
+	Verified bool
+	// Digest is the chart's verified digest, in "sha256:<hex>" form.
+	Digest string
+}